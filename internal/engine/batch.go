@@ -0,0 +1,196 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"kvstore/internal/store"
+)
+
+type opKind int
+
+const (
+	opSet opKind = iota
+	opSetTTL
+	opDelete
+)
+
+// bufferedOp is a write buffered in a Batch until Commit.
+type bufferedOp struct {
+	kind       opKind
+	value      []byte
+	ttlSeconds int64
+}
+
+// appliedOp is a write that made it through Commit's transaction, carrying
+// whatever the store actually computed (e.g. the real expiry time) so the
+// cache is updated with the same values the DB now holds.
+type appliedOp struct {
+	key       string
+	kind      opKind
+	value     []byte
+	expiresAt *time.Time
+}
+
+// Batch is an all-or-nothing multi-key write, modeled after Syncbase's
+// BeginBatch/Commit/Abort. Writes are buffered locally; Get consults that
+// buffer first, so a batch sees its own uncommitted writes.
+type Batch struct {
+	engine *Engine
+	buffer map[string]bufferedOp
+	order  []string
+	done   bool
+}
+
+// BeginBatch starts a new batch. Buffered writes only reach the store (and
+// cache) on Commit.
+func (e *Engine) BeginBatch(ctx context.Context) (*Batch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &Batch{
+		engine: e,
+		buffer: make(map[string]bufferedOp),
+	}, nil
+}
+
+func (b *Batch) record(key string, op bufferedOp) {
+	if _, exists := b.buffer[key]; !exists {
+		b.order = append(b.order, key)
+	}
+	b.buffer[key] = op
+}
+
+// Get returns the batch's own uncommitted write for key if there is one,
+// otherwise it falls through to the engine's normal read path.
+func (b *Batch) Get(key string) ([]byte, error) {
+	if b.done {
+		return nil, fmt.Errorf("batch already closed")
+	}
+	if op, ok := b.buffer[key]; ok {
+		if op.kind == opDelete {
+			return nil, nil
+		}
+		return op.value, nil
+	}
+	return b.engine.Get(key)
+}
+
+// Set buffers a write without TTL.
+func (b *Batch) Set(key string, value []byte) error {
+	if b.done {
+		return fmt.Errorf("batch already closed")
+	}
+	b.record(key, bufferedOp{kind: opSet, value: value})
+	return nil
+}
+
+// SetWithTTL buffers a write with a TTL in seconds.
+func (b *Batch) SetWithTTL(key string, value []byte, ttlSeconds int64) error {
+	if b.done {
+		return fmt.Errorf("batch already closed")
+	}
+	b.record(key, bufferedOp{kind: opSetTTL, value: value, ttlSeconds: ttlSeconds})
+	return nil
+}
+
+// Delete buffers a soft delete.
+func (b *Batch) Delete(key string) error {
+	if b.done {
+		return fmt.Errorf("batch already closed")
+	}
+	b.record(key, bufferedOp{kind: opDelete})
+	return nil
+}
+
+// Commit applies every buffered write inside a single store transaction.
+// Retention policies are applied the same way they are for Engine.Set/
+// SetWithTTL, so a key ends up with the same TTL whether it was written
+// directly or through a batch. The cache is only updated after the
+// transaction succeeds, so concurrent readers never observe a
+// partially-applied batch.
+func (b *Batch) Commit() error {
+	if b.done {
+		return fmt.Errorf("batch already closed")
+	}
+	b.done = true
+
+	tx, ok := b.engine.store.(store.Transactional)
+	if !ok {
+		return fmt.Errorf("batch commit requires a transactional store backend")
+	}
+
+	var applied []appliedOp
+	err := tx.WithTx(func(txStore store.TxStore) error {
+		applied = nil
+		for _, key := range b.order {
+			op := b.buffer[key]
+			switch op.kind {
+			case opSet:
+				// Same retention handling as Engine.Set: a policy match
+				// fills in a default TTL so a batched write doesn't end up
+				// permanent just because the caller didn't pass one.
+				if ttlSeconds, ok := b.engine.defaultTTLForSet(key); ok {
+					expiresAt, err := txStore.SetWithTTL(key, op.value, ttlSeconds)
+					if err != nil {
+						return err
+					}
+					applied = append(applied, appliedOp{key: key, kind: opSetTTL, value: op.value, expiresAt: &expiresAt})
+					continue
+				}
+				if err := txStore.Set(key, op.value); err != nil {
+					return err
+				}
+				applied = append(applied, appliedOp{key: key, kind: opSet, value: op.value})
+
+			case opSetTTL:
+				// Same retention handling as Engine.SetWithTTL: cap at the
+				// matching policy's MaxTTL.
+				ttlSeconds := b.engine.capTTL(key, op.ttlSeconds)
+				expiresAt, err := txStore.SetWithTTL(key, op.value, ttlSeconds)
+				if err != nil {
+					return err
+				}
+				applied = append(applied, appliedOp{key: key, kind: opSetTTL, value: op.value, expiresAt: &expiresAt})
+
+			case opDelete:
+				if err := txStore.SoftDelete(key); err != nil {
+					return err
+				}
+				applied = append(applied, appliedOp{key: key, kind: opDelete})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, a := range applied {
+		switch a.kind {
+		case opSet:
+			b.engine.cache.Set(a.key, a.value, nil)
+		case opSetTTL:
+			b.engine.cache.Set(a.key, a.value, a.expiresAt)
+		case opDelete:
+			b.engine.cache.Delete(a.key)
+		}
+	}
+	return nil
+}
+
+// Abort discards the batch. Nothing was ever written to the store, but any
+// keys the batch buffered writes for are invalidated in the cache so a
+// stale Get can't outlive the aborted batch.
+func (b *Batch) Abort() error {
+	if b.done {
+		return fmt.Errorf("batch already closed")
+	}
+	b.done = true
+
+	for key := range b.buffer {
+		b.engine.cache.Delete(key)
+	}
+	return nil
+}
@@ -2,23 +2,28 @@ package engine
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"kvstore/internal/cache"
+	"kvstore/internal/retention"
 	"kvstore/internal/store"
 )
 
 // Engine is the main KV engine that coordinates cache and store
 type Engine struct {
-	cache *cache.Cache
-	store *store.Store
+	cache     *cache.Cache
+	store     store.Store
+	retention *retention.Set
 }
 
-// New creates a new Engine instance
-func New(c *cache.Cache, s *store.Store) *Engine {
+// New creates a new Engine instance. retention may be nil, or an empty
+// retention.NewSet(), to disable policy-driven TTL defaults entirely.
+func New(c *cache.Cache, s store.Store, r *retention.Set) *Engine {
 	return &Engine{
-		cache: c,
-		store: s,
+		cache:     c,
+		store:     s,
+		retention: r,
 	}
 }
 
@@ -66,8 +71,39 @@ func (e *Engine) Get(key string) ([]byte, error) {
 	return value, nil
 }
 
-// Set stores a key-value pair without TTL
+// defaultTTLForSet returns the TTL a policy-less Set should actually use:
+// the matching retention policy's DefaultTTL, if one matches key and that
+// default is positive. It's shared by Set and Batch.Commit (for buffered
+// opSet writes) so both end up applying retention identically.
+func (e *Engine) defaultTTLForSet(key string) (ttlSeconds int64, ok bool) {
+	if e.retention == nil {
+		return 0, false
+	}
+	ttlSeconds, matched := e.retention.EffectiveTTL(key, 0)
+	return ttlSeconds, matched && ttlSeconds > 0
+}
+
+// capTTL caps ttlSeconds at the matching retention policy's MaxTTL, if any.
+// It's shared by SetWithTTL and Batch.Commit (for buffered opSetTTL writes)
+// so both end up applying retention identically.
+func (e *Engine) capTTL(key string, ttlSeconds int64) int64 {
+	if e.retention == nil {
+		return ttlSeconds
+	}
+	if capped, ok := e.retention.EffectiveTTL(key, ttlSeconds); ok {
+		return capped
+	}
+	return ttlSeconds
+}
+
+// Set stores a key-value pair. If a retention policy matches key, its
+// DefaultTTL is applied so the key doesn't end up permanent just because
+// the caller didn't think to pass a TTL.
 func (e *Engine) Set(key string, value []byte) error {
+	if ttlSeconds, ok := e.defaultTTLForSet(key); ok {
+		return e.SetWithTTL(key, value, ttlSeconds)
+	}
+
 	start := time.Now()
 
 	// Write to DB first (source of truth)
@@ -82,8 +118,11 @@ func (e *Engine) Set(key string, value []byte) error {
 	return nil
 }
 
-// SetWithTTL stores a key-value pair with a TTL in seconds
+// SetWithTTL stores a key-value pair with a TTL in seconds. If a retention
+// policy matches key, ttlSeconds is capped at the policy's MaxTTL.
 func (e *Engine) SetWithTTL(key string, value []byte, ttlSeconds int64) error {
+	ttlSeconds = e.capTTL(key, ttlSeconds)
+
 	start := time.Now()
 
 	// Write to DB first
@@ -116,12 +155,50 @@ func (e *Engine) Delete(key string) error {
 	return nil
 }
 
+// Incr atomically adds 1 to the integer stored at key and returns the new value.
+func (e *Engine) Incr(key string) (int64, error) {
+	return e.IncrBy(key, 1)
+}
+
+// Decr atomically subtracts 1 from the integer stored at key and returns the new value.
+func (e *Engine) Decr(key string) (int64, error) {
+	return e.IncrBy(key, -1)
+}
+
+// IncrBy atomically adds delta to the integer stored at key and returns the
+// new value. A missing/tombstoned/expired key is treated as 0.
+//
+// This can't be built safely on top of Get/Set: two callers reading then
+// writing back would race. The store backend does the whole
+// read-modify-write in one round-trip, and the cache entry is replaced with
+// the authoritative result — including whatever TTL the backend preserved
+// for the key — only after that round-trip succeeds, so concurrent readers
+// never observe a stale count or a count that outlives its real expiry.
+func (e *Engine) IncrBy(key string, delta int64) (int64, error) {
+	start := time.Now()
+
+	counter, ok := e.store.(store.Counter)
+	if !ok {
+		return 0, fmt.Errorf("IncrBy: store backend does not support atomic counters")
+	}
+
+	newValue, expiresAt, err := counter.IncrBy(key, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	e.cache.Set(key, []byte(strconv.FormatInt(newValue, 10)), expiresAt)
+
+	fmt.Printf("[INCR] key=%s delta=%d new=%d time=%s\n", key, delta, newValue, time.Since(start))
+	return newValue, nil
+}
+
 // GetCache returns the underlying cache (for testing/debugging)
 func (e *Engine) GetCache() *cache.Cache {
 	return e.cache
 }
 
 // GetStore returns the underlying store (for testing/debugging)
-func (e *Engine) GetStore() *store.Store {
+func (e *Engine) GetStore() store.Store {
 	return e.store
 }
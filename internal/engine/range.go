@@ -0,0 +1,47 @@
+package engine
+
+import "context"
+
+// rangeBatchSize is how many keys Range pulls from the store per Scan call.
+const rangeBatchSize = 100
+
+// Range streams every alive key with the given prefix, in lexicographic
+// order, calling fn for each one. It stops early if fn returns false, if
+// ctx is done, or once the prefix is exhausted.
+func (e *Engine) Range(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error {
+	startKey := prefix
+	skipStart := false
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		kvs, err := e.store.Scan(prefix, startKey, rangeBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(kvs) == 0 {
+			return nil
+		}
+
+		for _, kv := range kvs {
+			if skipStart && kv.Key == startKey {
+				continue // already handed to fn on the previous page
+			}
+			if !fn(kv.Key, kv.Value) {
+				return nil
+			}
+		}
+
+		if len(kvs) < rangeBatchSize {
+			return nil
+		}
+
+		// Next page starts at the last key we saw (Scan's startKey is
+		// inclusive, so that key is skipped above). Appending a NUL byte to
+		// step past it would be rejected by Postgres text parameters.
+		startKey = kvs[len(kvs)-1].Key
+		skipStart = true
+	}
+}
@@ -5,66 +5,95 @@ import (
 	"time"
 
 	"kvstore/internal/cache"
+	"kvstore/internal/retention"
 	"kvstore/internal/store"
 )
 
+// resumeCursorMetaKey is where the worker persists the last key it deleted,
+// so a restart resumes the sweep instead of starting over.
+const resumeCursorMetaKey = "expiry_resume_cursor"
+
 // Config holds the configuration for the expiry worker
 type Config struct {
-	// Interval between expiry checks
-	Interval time.Duration
-
-	// SampleSize is the number of keys to sample each cycle
-	SampleSize int
+	// TargetSweepInterval is how long a full pass over the TTL keyspace
+	// should take. The worker speeds up when there are many keys with TTL
+	// and idles when there are few (cf. Cockroach's replica scanner).
+	TargetSweepInterval time.Duration
 
-	// ExpiryThreshold is the ratio of expired keys that triggers aggressive cleanup
-	// e.g., 0.25 means if >25% of sampled keys are expired, run hard delete
-	ExpiryThreshold float64
+	// MinInterval is the floor on how often the worker runs a cycle, even
+	// when the keyspace is huge.
+	MinInterval time.Duration
 
 	// DeleteBatchSize is the max number of keys to hard delete per cycle
 	DeleteBatchSize int
+
+	// CheckpointInterval is how often the resume cursor is persisted to
+	// the store (cf. Cockroach's maybeWriteResumeSpan) rather than on
+	// every single cycle.
+	CheckpointInterval time.Duration
 }
 
-// DefaultConfig returns sensible defaults (Redis-like)
+// DefaultConfig returns sensible defaults: sweep the whole TTL keyspace
+// roughly once an hour.
 func DefaultConfig() Config {
 	return Config{
-		Interval:        5 * time.Second,
-		SampleSize:      20,
-		ExpiryThreshold: 0.25, // 25%
-		DeleteBatchSize: 500,
+		TargetSweepInterval: time.Hour,
+		MinInterval:         100 * time.Millisecond,
+		DeleteBatchSize:     500,
+		CheckpointInterval:  30 * time.Second,
 	}
 }
 
 // Worker handles background expiration of keys
 type Worker struct {
-	store  *store.Store
-	cache  *cache.Cache
-	config Config
-	stopCh chan struct{}
+	store    store.Store
+	cache    *cache.Cache
+	config   Config
+	policies *retention.Set // nil disables policy-driven mode
+	stopCh   chan struct{}
+
+	cursor         string
+	lastCheckpoint time.Time
 }
 
-// NewWorker creates a new expiry worker
-func NewWorker(s *store.Store, c *cache.Cache, cfg Config) *Worker {
+// NewWorker creates a new expiry worker. policies may be nil, in which case
+// the worker paces every cycle off config.TargetSweepInterval alone. With a
+// non-nil, non-empty policies, the worker runs in policy-driven mode (see
+// targetSweepInterval).
+func NewWorker(s store.Store, c *cache.Cache, cfg Config, policies *retention.Set) *Worker {
 	return &Worker{
-		store:  s,
-		cache:  c,
-		config: cfg,
-		stopCh: make(chan struct{}),
+		store:    s,
+		cache:    c,
+		config:   cfg,
+		policies: policies,
+		stopCh:   make(chan struct{}),
 	}
 }
 
 // Start begins the background expiry process
 func (w *Worker) Start() {
+	cursor, err := w.store.GetMeta(resumeCursorMetaKey)
+	if err != nil {
+		fmt.Printf("[EXPIRY] failed to load resume cursor, starting from scratch: %v\n", err)
+	} else {
+		w.cursor = cursor
+	}
+
 	go func() {
-		ticker := time.NewTicker(w.config.Interval)
-		defer ticker.Stop()
+		policyCount := 0
+		if w.policies != nil {
+			policyCount = len(w.policies.Policies())
+		}
+		fmt.Printf("[EXPIRY] Worker started (targetSweep=%s min=%s batch=%d cursor=%q policies=%d)\n",
+			w.config.TargetSweepInterval, w.config.MinInterval, w.config.DeleteBatchSize, w.cursor, policyCount)
 
-		fmt.Printf("[EXPIRY] Worker started (interval=%s, sample=%d, threshold=%.0f%%)\n",
-			w.config.Interval, w.config.SampleSize, w.config.ExpiryThreshold*100)
+		timer := time.NewTimer(w.config.MinInterval)
+		defer timer.Stop()
 
 		for {
 			select {
-			case <-ticker.C:
-				w.runCycle()
+			case <-timer.C:
+				timer.Reset(w.runCycle())
 			case <-w.stopCh:
 				fmt.Println("[EXPIRY] Worker stopped")
 				return
@@ -78,42 +107,111 @@ func (w *Worker) Stop() {
 	close(w.stopCh)
 }
 
-// runCycle performs one expiry check cycle (Redis-style sampling)
-func (w *Worker) runCycle() {
-	// Step 1: Sample keys with TTL
-	total, expired, err := w.store.SampleExpiredKeys(w.config.SampleSize)
+// runCycle performs one cursor-bounded hard-delete sweep and returns how
+// long to wait before the next cycle.
+func (w *Worker) runCycle() time.Duration {
+	total, err := w.store.CountKeysWithTTL()
 	if err != nil {
-		fmt.Printf("[EXPIRY] sample error: %v\n", err)
-		return
+		fmt.Printf("[EXPIRY] count error: %v\n", err)
+		return w.config.MinInterval
 	}
-
 	if total == 0 {
-		return // No keys with TTL
+		return w.config.MinInterval // nothing to do; check back at the floor rate
 	}
 
-	ratio := float64(expired) / float64(total)
+	deleted, lastKey, err := w.store.HardDeleteBatch(w.cursor, w.config.DeleteBatchSize)
+	if err != nil {
+		fmt.Printf("[EXPIRY] hard delete error: %v\n", err)
+		return w.config.MinInterval
+	}
 
-	// Step 2: Check if we should run aggressive cleanup
-	if ratio < w.config.ExpiryThreshold {
-		fmt.Printf("[EXPIRY] sampled=%d expired=%d (%.1f%%) → skipping cleanup\n",
-			total, expired, ratio*100)
-		return
+	if lastKey != "" {
+		w.cursor = lastKey
+	} else {
+		// Nothing expired past the cursor: we've swept to the end of the
+		// keyspace, so wrap around and start the next pass from scratch.
+		w.cursor = ""
 	}
+	w.maybeCheckpoint()
 
-	fmt.Printf("[EXPIRY] sampled=%d expired=%d (%.1f%%) → running hard delete\n",
-		total, expired, ratio*100)
+	fmt.Printf("[EXPIRY] ttl_keys=%d deleted=%d cursor=%q\n", total, deleted, w.cursor)
 
-	// Step 3: Run batched hard delete
-	deleted, err := w.store.HardDeleteBatch(w.config.DeleteBatchSize)
-	if err != nil {
-		fmt.Printf("[EXPIRY] hard delete error: %v\n", err)
+	return w.nextWait(total)
+}
+
+// maybeCheckpoint persists the resume cursor, but only once per
+// CheckpointInterval so every cycle doesn't pay for a write.
+func (w *Worker) maybeCheckpoint() {
+	if time.Since(w.lastCheckpoint) < w.config.CheckpointInterval {
 		return
 	}
+	if err := w.store.SetMeta(resumeCursorMetaKey, w.cursor); err != nil {
+		fmt.Printf("[EXPIRY] checkpoint error: %v\n", err)
+		return
+	}
+	w.lastCheckpoint = time.Now()
+}
+
+// targetSweepInterval returns how long a full sweep should take. Outside of
+// policy-driven mode this is just config.TargetSweepInterval. In
+// policy-driven mode it's capped at the smallest DefaultTTL among
+// registered policies, so the single global sweep speeds up to keep pace
+// with whichever prefix churns fastest.
+//
+// Known limitation: this only speeds up the global cycle; HardDeleteBatch
+// still walks the whole TTL keyspace in plain key order with no notion of
+// which prefix a key belongs to, so a fast-churning prefix that happens to
+// sort late in the keyspace gets no priority within a cycle, only a faster
+// (but still prefix-blind) tick overall. Real prioritization would need a
+// per-policy cursor and a store.Store.HardDeleteBatch that can filter by
+// prefix, processed most-urgent-policy-first.
+func (w *Worker) targetSweepInterval() time.Duration {
+	target := w.config.TargetSweepInterval
+	if w.policies == nil {
+		return target
+	}
+
+	// Policies are sorted fastest-churning (smallest DefaultTTL) first, but
+	// a DefaultTTL of 0 means "no default" (cf. retention.Policy) and still
+	// sorts first, so the smallest strictly-positive DefaultTTL has to be
+	// found by scanning rather than trusting index 0.
+	for _, p := range w.policies.Policies() {
+		if p.DefaultTTL > 0 && p.DefaultTTL < target {
+			target = p.DefaultTTL
+		}
+	}
+	return target
+}
+
+// nextWait computes how long to sleep before the next cycle so that a full
+// sweep of totalKeysWithTTL keys takes roughly targetSweepInterval(): the
+// cycle wait is the target divided across however many batches remain, not
+// a fixed per-key budget multiplied by the batch count (which, once the
+// per-key budget hits the floor, grows linearly with totalKeysWithTTL,
+// making a full sweep take quadratically longer as the keyspace grows
+// instead of staying on target).
+func (w *Worker) nextWait(totalKeysWithTTL int64) time.Duration {
+	batchesRemaining := (totalKeysWithTTL + int64(w.config.DeleteBatchSize) - 1) / int64(w.config.DeleteBatchSize)
+	if batchesRemaining < 1 {
+		batchesRemaining = 1
+	}
+
+	wait := w.targetSweepInterval() / time.Duration(batchesRemaining)
+	if wait < w.config.MinInterval {
+		wait = w.config.MinInterval
+	}
 
-	fmt.Printf("[EXPIRY] hard deleted %d keys\n", deleted)
+	return wait
 }
 
 // ForceCleanup manually triggers a hard delete cycle (for testing)
 func (w *Worker) ForceCleanup() (int64, error) {
-	return w.store.HardDeleteBatch(w.config.DeleteBatchSize)
+	deleted, lastKey, err := w.store.HardDeleteBatch(w.cursor, w.config.DeleteBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if lastKey != "" {
+		w.cursor = lastKey
+	}
+	return deleted, nil
 }
@@ -0,0 +1,156 @@
+// Package retention implements InfluxDB-style retention policies: named
+// rules that map a key prefix to a default/max TTL, so operators can set a
+// TTL bucket once instead of passing ttlSeconds on every write.
+package retention
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy is a single named retention rule, modeled on InfluxDB's
+// RetentionPolicyInfo: every key under KeyPrefix gets DefaultTTL unless the
+// caller supplies its own TTL, which is then capped at MaxTTL. A zero
+// DefaultTTL means "no default" and a zero MaxTTL means "no cap".
+type Policy struct {
+	Name       string
+	KeyPrefix  string
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+// MarshalBinary gob-encodes p. Backends persist policies as this opaque
+// blob rather than separate columns/fields, the same way store/bolt's row
+// values are gob-encoded, so a policy can be shipped or restored between
+// backends without a schema migration.
+func (p Policy) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a Policy previously produced by MarshalBinary.
+func (p *Policy) UnmarshalBinary(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(p)
+}
+
+// Store persists the policy set. Backends that want to offer policy-driven
+// defaults implement this the same way they implement store.Transactional
+// or store.Counter: an optional capability, type-asserted where needed.
+type Store interface {
+	// Policies returns every registered policy.
+	Policies() ([]Policy, error)
+
+	// SavePolicy creates or replaces the policy with the given name.
+	SavePolicy(p Policy) error
+}
+
+// Set is an in-memory, read-optimized view of the policy set, consulted on
+// every Engine.Set/SetWithTTL call. It's hydrated from a Store at startup
+// via Load and kept current with Register.
+type Set struct {
+	mu       sync.RWMutex
+	policies []Policy // sorted by KeyPrefix length, longest first
+}
+
+// NewSet creates an empty policy set, under which Engine.Set/SetWithTTL
+// behave exactly as if retention didn't exist.
+func NewSet() *Set {
+	return &Set{}
+}
+
+// Load hydrates a new Set from every policy currently in s.
+func Load(s Store) (*Set, error) {
+	policies, err := s.Policies()
+	if err != nil {
+		return nil, err
+	}
+	set := NewSet()
+	for _, p := range policies {
+		set.Register(p)
+	}
+	return set, nil
+}
+
+// Register adds or replaces a policy by name in the in-memory set. It does
+// not persist p; callers that want the policy to survive a restart should
+// also call Store.SavePolicy.
+func (s *Set) Register(p Policy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.policies {
+		if existing.Name == p.Name {
+			s.policies[i] = p
+			s.resort()
+			return
+		}
+	}
+	s.policies = append(s.policies, p)
+	s.resort()
+}
+
+// resort keeps policies ordered by KeyPrefix length descending, so Match
+// prefers the most specific prefix (e.g. "logs/debug/" over "logs/").
+func (s *Set) resort() {
+	sort.Slice(s.policies, func(i, j int) bool {
+		return len(s.policies[i].KeyPrefix) > len(s.policies[j].KeyPrefix)
+	})
+}
+
+// Match returns the most specific registered policy whose KeyPrefix is a
+// prefix of key.
+func (s *Set) Match(key string) (Policy, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, p := range s.policies {
+		if strings.HasPrefix(key, p.KeyPrefix) {
+			return p, true
+		}
+	}
+	return Policy{}, false
+}
+
+// Policies returns a snapshot of every registered policy, ordered by
+// DefaultTTL ascending (a zero DefaultTTL, meaning no default, sorts
+// first). The expiry worker's policy-driven mode takes only the smallest
+// positive DefaultTTL from it to pace its sweep; it does not sweep prefixes
+// in this order.
+func (s *Set) Policies() []Policy {
+	s.mu.RLock()
+	out := make([]Policy, len(s.policies))
+	copy(out, s.policies)
+	s.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].DefaultTTL < out[j].DefaultTTL
+	})
+	return out
+}
+
+// EffectiveTTL applies the policy matching key (if any) to a caller-supplied
+// TTL. requestedTTLSeconds <= 0 means "no TTL supplied": the matching
+// policy's DefaultTTL is used instead. A positive requestedTTLSeconds is
+// capped at the policy's MaxTTL. ok is false when no policy matches key, in
+// which case requestedTTLSeconds is returned unchanged.
+func (s *Set) EffectiveTTL(key string, requestedTTLSeconds int64) (ttlSeconds int64, ok bool) {
+	p, matched := s.Match(key)
+	if !matched {
+		return requestedTTLSeconds, false
+	}
+
+	if requestedTTLSeconds <= 0 {
+		return int64(p.DefaultTTL.Seconds()), true
+	}
+	if maxSeconds := int64(p.MaxTTL.Seconds()); p.MaxTTL > 0 && requestedTTLSeconds > maxSeconds {
+		return maxSeconds, true
+	}
+	return requestedTTLSeconds, true
+}
@@ -0,0 +1,436 @@
+// Package bolt is an embedded, single-node implementation of store.Store
+// backed by BoltDB (go.etcd.io/bbolt). It requires no external database,
+// which makes it a good fit for small deployments and CI.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"kvstore/internal/retention"
+	"kvstore/internal/store"
+)
+
+var (
+	dataBucket      = []byte("kv")
+	ttlBucket       = []byte("kv_ttl_index")
+	metaBucket      = []byte("kv_meta")
+	retentionBucket = []byte("kv_retention_policy")
+)
+
+// row is the value stored in dataBucket for every key.
+type row struct {
+	Value     []byte
+	ExpiresAt int64 // unix nanoseconds, 0 means no TTL
+}
+
+// tombstoneExpiresAt is the row.ExpiresAt SoftDelete writes. It can't be
+// store.TombstoneTime.UnixNano(), which is 0 and so would read as "no TTL";
+// one nanosecond past the epoch is just as far in the past.
+const tombstoneExpiresAt int64 = 1
+
+// Bolt is a BoltDB-backed Store.
+type Bolt struct {
+	db *bolt.DB
+}
+
+// Open creates/opens a Bolt-backed Store at path.
+func Open(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(dataBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(ttlBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(metaBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(retentionBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+// Close releases the underlying BoltDB file.
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
+
+// ttlIndexKey encodes expiresAt (big-endian, so lexicographic order sorts
+// chronologically) followed by the key, so the ttl bucket is naturally
+// ordered by expiry and its key count is a cheap stand-in for
+// CountKeysWithTTL.
+func ttlIndexKey(expiresAt int64, key string) []byte {
+	buf := make([]byte, 8+len(key))
+	binary.BigEndian.PutUint64(buf, uint64(expiresAt))
+	copy(buf[8:], key)
+	return buf
+}
+
+func encodeRow(r row) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeRow(data []byte) (row, error) {
+	var r row
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&r)
+	return r, err
+}
+
+// Get retrieves a key. Returns (nil, nil, nil) if missing, tombstoned, or expired.
+func (b *Bolt) Get(key string) ([]byte, *time.Time, error) {
+	var value []byte
+	var expiresAt *time.Time
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		value, expiresAt = getTx(tx, key)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, expiresAt, nil
+}
+
+func getTx(tx *bolt.Tx, key string) ([]byte, *time.Time) {
+	data := tx.Bucket(dataBucket).Get([]byte(key))
+	if data == nil {
+		return nil, nil
+	}
+	r, err := decodeRow(data)
+	if err != nil {
+		return nil, nil
+	}
+	if r.ExpiresAt != 0 {
+		t := time.Unix(0, r.ExpiresAt).UTC()
+		if !t.After(time.Now()) {
+			return nil, nil // expired/tombstoned
+		}
+		return r.Value, &t
+	}
+	return r.Value, nil
+}
+
+// Set stores a key-value pair without TTL.
+func (b *Bolt) Set(key string, value []byte) error {
+	return b.put(key, value, 0)
+}
+
+// SetWithTTL stores a key-value pair with a TTL and returns the computed expiry time.
+func (b *Bolt) SetWithTTL(key string, value []byte, ttlSeconds int64) (time.Time, error) {
+	expiresAt := time.Now().Add(time.Duration(ttlSeconds) * time.Second)
+	return expiresAt, b.put(key, value, expiresAt.UnixNano())
+}
+
+// SoftDelete marks a key as deleted by setting its expiry to
+// tombstoneExpiresAt, a time in the past.
+func (b *Bolt) SoftDelete(key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(dataBucket).Get([]byte(key))
+		var value []byte
+		if data != nil {
+			if r, err := decodeRow(data); err == nil {
+				value = r.Value
+			}
+		}
+		return b.putTx(tx, key, value, tombstoneExpiresAt)
+	})
+}
+
+// boltTx is the store.TxStore handed to Transactional.WithTx callbacks; it
+// runs every operation against a single *bolt.Tx.
+type boltTx struct {
+	b  *Bolt
+	tx *bolt.Tx
+}
+
+func (t *boltTx) Get(key string) ([]byte, *time.Time, error) {
+	value, expiresAt := getTx(t.tx, key)
+	return value, expiresAt, nil
+}
+
+func (t *boltTx) Set(key string, value []byte) error {
+	return t.b.putTx(t.tx, key, value, 0)
+}
+
+func (t *boltTx) SetWithTTL(key string, value []byte, ttlSeconds int64) (time.Time, error) {
+	expiresAt := time.Now().Add(time.Duration(ttlSeconds) * time.Second)
+	return expiresAt, t.b.putTx(t.tx, key, value, expiresAt.UnixNano())
+}
+
+func (t *boltTx) SoftDelete(key string) error {
+	value, _ := getTx(t.tx, key)
+	return t.b.putTx(t.tx, key, value, tombstoneExpiresAt)
+}
+
+// WithTx runs fn inside a single BoltDB read-write transaction, committing
+// if it returns nil and rolling back otherwise (bolt.DB.Update already
+// gives us that behavior for free).
+func (b *Bolt) WithTx(fn func(tx store.TxStore) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(&boltTx{b: b, tx: tx})
+	})
+}
+
+// put writes key/value/expiresAt in its own transaction, keeping the ttl
+// index in sync.
+func (b *Bolt) put(key string, value []byte, expiresAt int64) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return b.putTx(tx, key, value, expiresAt)
+	})
+}
+
+func (b *Bolt) putTx(tx *bolt.Tx, key string, value []byte, expiresAt int64) error {
+	data := tx.Bucket(dataBucket)
+	ttl := tx.Bucket(ttlBucket)
+
+	// Drop any stale ttl-index entry for this key before writing the new one.
+	if old := data.Get([]byte(key)); old != nil {
+		if r, err := decodeRow(old); err == nil && r.ExpiresAt != 0 {
+			if err := ttl.Delete(ttlIndexKey(r.ExpiresAt, key)); err != nil {
+				return err
+			}
+		}
+	}
+
+	encoded, err := encodeRow(row{Value: value, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+	if err := data.Put([]byte(key), encoded); err != nil {
+		return err
+	}
+	if expiresAt != 0 {
+		if err := ttl.Put(ttlIndexKey(expiresAt, key), []byte(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HardDelete physically removes a key.
+func (b *Bolt) HardDelete(key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(dataBucket)
+		ttl := tx.Bucket(ttlBucket)
+
+		if old := data.Get([]byte(key)); old != nil {
+			if r, err := decodeRow(old); err == nil && r.ExpiresAt != 0 {
+				if err := ttl.Delete(ttlIndexKey(r.ExpiresAt, key)); err != nil {
+					return err
+				}
+			}
+		}
+		return data.Delete([]byte(key))
+	})
+}
+
+// HardDeleteBatch removes up to limit expired/tombstoned keys greater than
+// cursor, walking the data bucket in key order (bolt's own b+tree order)
+// inside one transaction, and returns how many were deleted along with the
+// last key deleted so the caller can resume the sweep from there next time.
+func (b *Bolt) HardDeleteBatch(cursor string, limit int) (int64, string, error) {
+	var deleted int64
+	var lastKey string
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(dataBucket)
+		ttl := tx.Bucket(ttlBucket)
+		now := time.Now().UnixNano()
+
+		seek := []byte(cursor)
+		if len(seek) > 0 {
+			seek = append(seek, 0) // first key strictly greater than cursor
+		}
+
+		c := data.Cursor()
+		var toDelete []string
+		for k, v := c.Seek(seek); k != nil && len(toDelete) < limit; k, v = c.Next() {
+			r, err := decodeRow(v)
+			if err != nil {
+				return err
+			}
+			if r.ExpiresAt == 0 || r.ExpiresAt > now {
+				continue // alive
+			}
+			toDelete = append(toDelete, string(k))
+		}
+
+		for _, key := range toDelete {
+			if old := data.Get([]byte(key)); old != nil {
+				if r, err := decodeRow(old); err == nil && r.ExpiresAt != 0 {
+					if err := ttl.Delete(ttlIndexKey(r.ExpiresAt, key)); err != nil {
+						return err
+					}
+				}
+			}
+			if err := data.Delete([]byte(key)); err != nil {
+				return err
+			}
+			deleted++
+			lastKey = key
+		}
+		return nil
+	})
+
+	return deleted, lastKey, err
+}
+
+// CountKeysWithTTL returns how many keys currently carry a TTL (including
+// tombstones). Used by the expiry worker to pace its sweep.
+func (b *Bolt) CountKeysWithTTL() (int64, error) {
+	var count int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		count = int64(tx.Bucket(ttlBucket).Stats().KeyN)
+		return nil
+	})
+	return count, err
+}
+
+// GetMeta returns a small piece of worker metadata, or "" if unset.
+func (b *Bolt) GetMeta(metaKey string) (string, error) {
+	var value string
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(metaBucket).Get([]byte(metaKey)); v != nil {
+			value = string(v)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// SetMeta persists a small piece of worker metadata.
+func (b *Bolt) SetMeta(metaKey string, value string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put([]byte(metaKey), []byte(value))
+	})
+}
+
+// Policies returns every registered retention policy.
+func (b *Bolt) Policies() ([]retention.Policy, error) {
+	var out []retention.Policy
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(retentionBucket).ForEach(func(_, v []byte) error {
+			var p retention.Policy
+			if err := p.UnmarshalBinary(v); err != nil {
+				return err
+			}
+			out = append(out, p)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// SavePolicy creates or replaces the policy with the given name, storing it
+// gob-encoded (see retention.Policy.MarshalBinary) the same way dataBucket
+// rows are.
+func (b *Bolt) SavePolicy(p retention.Policy) error {
+	data, err := p.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(retentionBucket).Put([]byte(p.Name), data)
+	})
+}
+
+// Scan returns up to limit alive keys with the given prefix, starting at
+// startKey (inclusive), ordered lexicographically by key.
+func (b *Bolt) Scan(prefix string, startKey string, limit int) ([]store.KV, error) {
+	var out []store.KV
+	now := time.Now()
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(dataBucket).Cursor()
+		prefixBytes := []byte(prefix)
+		for k, v := c.Seek([]byte(startKey)); k != nil && len(out) < limit; k, v = c.Next() {
+			if !bytes.HasPrefix(k, prefixBytes) {
+				if bytes.Compare(k, prefixBytes) > 0 {
+					break
+				}
+				continue
+			}
+			r, err := decodeRow(v)
+			if err != nil {
+				return err
+			}
+			var expiresAt *time.Time
+			if r.ExpiresAt != 0 {
+				t := time.Unix(0, r.ExpiresAt).UTC()
+				if !t.After(now) {
+					continue
+				}
+				expiresAt = &t
+			}
+			out = append(out, store.KV{Key: string(k), Value: r.Value, ExpiresAt: expiresAt})
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Watch streams the value of key every time it changes. Bolt has no
+// built-in change feed, so this polls Get.
+func (b *Bolt) Watch(ctx context.Context, key string) (<-chan []byte, error) {
+	return store.PollWatch(ctx, 200*time.Millisecond, func() ([]byte, error) {
+		value, _, err := b.Get(key)
+		return value, err
+	}), nil
+}
+
+// IncrBy atomically adds delta to the integer stored at key and returns the
+// new value, treating a missing/tombstoned/expired key as 0. The whole
+// read-modify-write happens inside a single bolt.Tx so concurrent IncrBy
+// calls never race.
+func (b *Bolt) IncrBy(key string, delta int64) (int64, *time.Time, error) {
+	var newValue int64
+	var newExpiresAt *time.Time
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		current, expiresAt := getTx(tx, key)
+
+		var cur int64
+		if current != nil {
+			parsed, err := strconv.ParseInt(string(current), 10, 64)
+			if err != nil {
+				return err
+			}
+			cur = parsed
+		} else {
+			expiresAt = nil // dead/absent key: reinitialize with no TTL
+		}
+
+		newValue = cur + delta
+		newExpiresAt = expiresAt
+
+		var expiresAtNano int64
+		if expiresAt != nil {
+			expiresAtNano = expiresAt.UnixNano()
+		}
+		return b.putTx(tx, key, []byte(strconv.FormatInt(newValue, 10)), expiresAtNano)
+	})
+
+	return newValue, newExpiresAt, err
+}
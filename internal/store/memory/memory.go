@@ -0,0 +1,332 @@
+// Package memory is an in-memory implementation of store.Store, intended
+// for tests and small deployments that don't need Postgres or Bolt.
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kvstore/internal/retention"
+	"kvstore/internal/store"
+)
+
+type record struct {
+	value     []byte
+	expiresAt *time.Time
+}
+
+// Memory is an in-memory Store backed by a sync.Map for values, a second
+// sync.Map for small worker metadata (e.g. the expiry worker's resume
+// cursor), a third for retention policies, and a fourth tracking just the
+// keys that currently carry a TTL (including tombstones). The expiry worker
+// sweeps only that last set, so its cost scales with the number of
+// keys-with-TTL instead of the whole keyspace.
+//
+// The TTL index is a plain set, not ordered by expiry: HardDeleteBatch
+// ranges over it and sorts it on every call (O(k log k) in the number of
+// keys-with-TTL), and CountKeysWithTTL ranges over it (O(k)). There is no
+// heap keyed by expiry, so the soonest-to-expire key is not found in
+// O(log n).
+type Memory struct {
+	data     sync.Map // string -> *record
+	meta     sync.Map // string -> string
+	policies sync.Map // string (policy name) -> []byte (gob-encoded retention.Policy)
+	ttlIndex sync.Map // string -> struct{}, keys currently carrying a TTL
+
+	// txMu serializes WithTx/IncrBy calls so a rollback can safely restore
+	// exactly the keys it touched.
+	txMu sync.Mutex
+}
+
+// indexTTL keeps ttlIndex in sync with a record write: present in the index
+// iff the record carries a TTL.
+func (m *Memory) indexTTL(key string, expiresAt *time.Time) {
+	if expiresAt != nil {
+		m.ttlIndex.Store(key, struct{}{})
+	} else {
+		m.ttlIndex.Delete(key)
+	}
+}
+
+// New creates a new in-memory Store.
+func New() *Memory {
+	return &Memory{}
+}
+
+// Get retrieves a key. Returns (nil, nil, nil) if missing, tombstoned, or expired.
+func (m *Memory) Get(key string) ([]byte, *time.Time, error) {
+	v, ok := m.data.Load(key)
+	if !ok {
+		return nil, nil, nil
+	}
+	rec := v.(*record)
+	if rec.expiresAt != nil && !time.Now().Before(*rec.expiresAt) {
+		return nil, nil, nil
+	}
+	return rec.value, rec.expiresAt, nil
+}
+
+// Set stores a key-value pair without TTL.
+func (m *Memory) Set(key string, value []byte) error {
+	m.data.Store(key, &record{value: value})
+	m.indexTTL(key, nil)
+	return nil
+}
+
+// SetWithTTL stores a key-value pair with a TTL and returns the computed expiry time.
+func (m *Memory) SetWithTTL(key string, value []byte, ttlSeconds int64) (time.Time, error) {
+	expiresAt := time.Now().Add(time.Duration(ttlSeconds) * time.Second)
+	m.data.Store(key, &record{value: value, expiresAt: &expiresAt})
+	m.indexTTL(key, &expiresAt)
+	return expiresAt, nil
+}
+
+// SoftDelete marks a key as deleted by setting its expiry to TombstoneTime.
+func (m *Memory) SoftDelete(key string) error {
+	v, ok := m.data.Load(key)
+	var value []byte
+	if ok {
+		value = v.(*record).value
+	}
+	m.data.Store(key, &record{value: value, expiresAt: &store.TombstoneTime})
+	m.indexTTL(key, &store.TombstoneTime)
+	return nil
+}
+
+// HardDelete physically removes a key.
+func (m *Memory) HardDelete(key string) error {
+	m.data.Delete(key)
+	m.ttlIndex.Delete(key)
+	return nil
+}
+
+// HardDeleteBatch removes up to limit expired/tombstoned keys whose key is
+// greater than cursor, walking ttlIndex (just the keys that carry a TTL,
+// not the whole keyspace) in lexicographic order, and returns how many were
+// deleted along with the last key deleted (for resuming the sweep next
+// cycle).
+func (m *Memory) HardDeleteBatch(cursor string, limit int) (int64, string, error) {
+	var keys []string
+	m.ttlIndex.Range(func(k, _ interface{}) bool {
+		keys = append(keys, k.(string))
+		return true
+	})
+	sort.Strings(keys)
+
+	now := time.Now()
+	var deleted int64
+	var lastKey string
+	for _, key := range keys {
+		if int(deleted) >= limit {
+			break
+		}
+		if key <= cursor {
+			continue
+		}
+		v, ok := m.data.Load(key)
+		if !ok {
+			m.ttlIndex.Delete(key)
+			continue
+		}
+		rec := v.(*record)
+		if rec.expiresAt == nil {
+			m.ttlIndex.Delete(key) // stale: overwritten without a TTL since indexed
+			continue
+		}
+		if rec.expiresAt.After(now) {
+			continue
+		}
+		m.data.Delete(key)
+		m.ttlIndex.Delete(key)
+		deleted++
+		lastKey = key
+	}
+	return deleted, lastKey, nil
+}
+
+// CountKeysWithTTL returns how many keys currently carry a TTL (including
+// tombstones), used by the expiry worker to pace its sweep.
+func (m *Memory) CountKeysWithTTL() (int64, error) {
+	var count int64
+	m.ttlIndex.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	return count, nil
+}
+
+// GetMeta returns a small piece of worker metadata, or "" if unset.
+func (m *Memory) GetMeta(metaKey string) (string, error) {
+	v, ok := m.meta.Load(metaKey)
+	if !ok {
+		return "", nil
+	}
+	return v.(string), nil
+}
+
+// SetMeta persists a small piece of worker metadata.
+func (m *Memory) SetMeta(metaKey string, value string) error {
+	m.meta.Store(metaKey, value)
+	return nil
+}
+
+// Policies returns every registered retention policy.
+func (m *Memory) Policies() ([]retention.Policy, error) {
+	var out []retention.Policy
+	var rangeErr error
+	m.policies.Range(func(_, v interface{}) bool {
+		var p retention.Policy
+		if err := p.UnmarshalBinary(v.([]byte)); err != nil {
+			rangeErr = err
+			return false
+		}
+		out = append(out, p)
+		return true
+	})
+	return out, rangeErr
+}
+
+// SavePolicy creates or replaces the policy with the given name, storing it
+// gob-encoded (see retention.Policy.MarshalBinary) the same way m.data
+// values would be if this backend gob-encoded them too.
+func (m *Memory) SavePolicy(p retention.Policy) error {
+	data, err := p.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	m.policies.Store(p.Name, data)
+	return nil
+}
+
+// Scan returns up to limit alive keys with the given prefix, starting at
+// startKey (inclusive), ordered lexicographically by key.
+func (m *Memory) Scan(prefix string, startKey string, limit int) ([]store.KV, error) {
+	var keys []string
+	m.data.Range(func(k, _ interface{}) bool {
+		keys = append(keys, k.(string))
+		return true
+	})
+	sort.Strings(keys)
+
+	now := time.Now()
+	var out []store.KV
+	for _, key := range keys {
+		if len(out) >= limit {
+			break
+		}
+		if key < startKey || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		v, ok := m.data.Load(key)
+		if !ok {
+			continue
+		}
+		rec := v.(*record)
+		if rec.expiresAt != nil && !now.Before(*rec.expiresAt) {
+			continue
+		}
+		out = append(out, store.KV{Key: key, Value: rec.value, ExpiresAt: rec.expiresAt})
+	}
+	return out, nil
+}
+
+// Watch streams the value of key every time it changes.
+func (m *Memory) Watch(ctx context.Context, key string) (<-chan []byte, error) {
+	return store.PollWatch(ctx, 100*time.Millisecond, func() ([]byte, error) {
+		value, _, err := m.Get(key)
+		return value, err
+	}), nil
+}
+
+// IncrBy atomically adds delta to the integer stored at key and returns the
+// new value, treating a missing/tombstoned/expired key as 0. txMu
+// serializes this against WithTx and other IncrBy calls so the
+// read-modify-write can't race.
+func (m *Memory) IncrBy(key string, delta int64) (int64, *time.Time, error) {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	value, expiresAt, err := m.Get(key)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var cur int64
+	if value != nil {
+		cur, err = strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return 0, nil, err
+		}
+	} else {
+		expiresAt = nil // dead/absent key: reinitialize with no TTL
+	}
+
+	newValue := cur + delta
+	m.data.Store(key, &record{value: []byte(strconv.FormatInt(newValue, 10)), expiresAt: expiresAt})
+	m.indexTTL(key, expiresAt)
+	return newValue, expiresAt, nil
+}
+
+// memTx is the store.TxStore handed to Transactional.WithTx callbacks. It
+// writes straight through to m, but remembers the prior value of every key
+// it touches so WithTx can roll back on abort.
+type memTx struct {
+	m       *Memory
+	touched map[string]*record // original value per key, nil meaning "was absent"
+}
+
+func (t *memTx) snapshot(key string) {
+	if _, ok := t.touched[key]; ok {
+		return
+	}
+	if v, ok := t.m.data.Load(key); ok {
+		t.touched[key] = v.(*record)
+	} else {
+		t.touched[key] = nil
+	}
+}
+
+func (t *memTx) Get(key string) ([]byte, *time.Time, error) { return t.m.Get(key) }
+
+func (t *memTx) Set(key string, value []byte) error {
+	t.snapshot(key)
+	return t.m.Set(key, value)
+}
+
+func (t *memTx) SetWithTTL(key string, value []byte, ttlSeconds int64) (time.Time, error) {
+	t.snapshot(key)
+	return t.m.SetWithTTL(key, value, ttlSeconds)
+}
+
+func (t *memTx) SoftDelete(key string) error {
+	t.snapshot(key)
+	return t.m.SoftDelete(key)
+}
+
+// WithTx runs fn against m, restoring every key it touched if fn returns an
+// error. There's no native transaction primitive for a sync.Map, so this
+// serializes transactions against each other and snapshots touched keys to
+// fake rollback.
+func (m *Memory) WithTx(fn func(tx store.TxStore) error) error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	tx := &memTx{m: m, touched: make(map[string]*record)}
+	if err := fn(tx); err != nil {
+		for key, orig := range tx.touched {
+			if orig == nil {
+				m.data.Delete(key)
+				m.ttlIndex.Delete(key)
+			} else {
+				m.data.Store(key, orig)
+				m.indexTTL(key, orig.expiresAt)
+			}
+		}
+		return err
+	}
+	return nil
+}
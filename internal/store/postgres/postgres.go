@@ -0,0 +1,362 @@
+// Package postgres is the PostgreSQL-backed implementation of store.Store.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"kvstore/internal/retention"
+	"kvstore/internal/store"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx, so the single-row
+// read/write helpers below work identically whether they run against the
+// pool directly or inside a WithTx transaction.
+type execer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Postgres handles all PostgreSQL operations for the KV store.
+type Postgres struct {
+	db *sql.DB
+}
+
+// New creates a new Postgres-backed Store.
+func New(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// Get retrieves a key from the database
+// Only returns keys that are alive (not tombstoned, not expired)
+// Returns (value, expiresAt, nil) if found
+// Returns (nil, nil, nil) if not found or expired/deleted
+func (s *Postgres) Get(key string) ([]byte, *time.Time, error) {
+	return get(s.db, key)
+}
+
+func get(q execer, key string) ([]byte, *time.Time, error) {
+	var value []byte
+	var expiresAt *time.Time
+
+	// Only select keys that are alive:
+	// - expires_at IS NULL (no TTL, not deleted)
+	// - expires_at > NOW() (TTL in future)
+	err := q.QueryRow(`
+		SELECT value, expires_at
+		FROM kv
+		WHERE key = $1
+		  AND (expires_at IS NULL OR expires_at > NOW())
+	`, key).Scan(&value, &expiresAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return value, expiresAt, nil
+}
+
+// Set stores a key-value pair without TTL
+func (s *Postgres) Set(key string, value []byte) error {
+	return set(s.db, key, value)
+}
+
+func set(q execer, key string, value []byte) error {
+	_, err := q.Exec(`
+		INSERT INTO kv (key, value, expires_at)
+		VALUES ($1, $2, NULL)
+		ON CONFLICT (key)
+		DO UPDATE SET value = $2, expires_at = NULL
+	`, key, value)
+	return err
+}
+
+// SetWithTTL stores a key-value pair with a TTL
+func (s *Postgres) SetWithTTL(key string, value []byte, ttlSeconds int64) (time.Time, error) {
+	return setWithTTL(s.db, key, value, ttlSeconds)
+}
+
+func setWithTTL(q execer, key string, value []byte, ttlSeconds int64) (time.Time, error) {
+	expiresAt := time.Now().Add(time.Duration(ttlSeconds) * time.Second)
+
+	_, err := q.Exec(`
+		INSERT INTO kv (key, value, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key)
+		DO UPDATE SET value = $2, expires_at = $3
+	`, key, value, expiresAt)
+
+	return expiresAt, err
+}
+
+// SoftDelete marks a key as deleted by setting expires_at to TombstoneTime
+// This is a cheap UPDATE operation - no B+tree rebalancing
+func (s *Postgres) SoftDelete(key string) error {
+	return softDelete(s.db, key)
+}
+
+func softDelete(q execer, key string) error {
+	_, err := q.Exec(`
+		UPDATE kv
+		SET expires_at = $1
+		WHERE key = $2
+	`, store.TombstoneTime, key)
+	return err
+}
+
+// txStore is the store.TxStore handed to Transactional.WithTx callbacks; it
+// runs every operation against a single *sql.Tx.
+type txStore struct {
+	tx *sql.Tx
+}
+
+func (t *txStore) Get(key string) ([]byte, *time.Time, error) { return get(t.tx, key) }
+func (t *txStore) Set(key string, value []byte) error         { return set(t.tx, key, value) }
+func (t *txStore) SetWithTTL(key string, value []byte, ttlSeconds int64) (time.Time, error) {
+	return setWithTTL(t.tx, key, value, ttlSeconds)
+}
+func (t *txStore) SoftDelete(key string) error { return softDelete(t.tx, key) }
+
+// WithTx runs fn inside a single sql.Tx, committing if it returns nil and
+// rolling back otherwise.
+func (s *Postgres) WithTx(fn func(tx store.TxStore) error) error {
+	sqlTx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(&txStore{tx: sqlTx}); err != nil {
+		if rbErr := sqlTx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	return sqlTx.Commit()
+}
+
+// HardDelete physically removes a key from the database
+// Use sparingly - causes B+tree rebalancing
+func (s *Postgres) HardDelete(key string) error {
+	_, err := s.db.Exec(`DELETE FROM kv WHERE key = $1`, key)
+	return err
+}
+
+// HardDeleteBatch removes up to limit expired/tombstoned keys greater than
+// cursor, ordered by key, and returns how many were deleted along with the
+// last key deleted so the caller can resume the sweep from there next time.
+// This is the only place where physical deletes should happen in bulk.
+func (s *Postgres) HardDeleteBatch(cursor string, limit int) (int64, string, error) {
+	rows, err := s.db.Query(`
+		SELECT key FROM kv
+		WHERE key > $1
+		  AND expires_at IS NOT NULL
+		  AND expires_at <= NOW()
+		ORDER BY key
+		LIMIT $2
+	`, cursor, limit)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return 0, "", err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, "", err
+	}
+	rows.Close()
+
+	if len(keys) == 0 {
+		return 0, "", nil
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM kv WHERE key = ANY($1)`, pq.Array(keys)); err != nil {
+		return 0, "", err
+	}
+
+	return int64(len(keys)), keys[len(keys)-1], nil
+}
+
+// CountKeysWithTTL returns how many keys currently carry a TTL (including
+// tombstones). Used by the expiry worker to pace its sweep.
+func (s *Postgres) CountKeysWithTTL() (int64, error) {
+	var count int64
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM kv WHERE expires_at IS NOT NULL`).Scan(&count)
+	return count, err
+}
+
+// GetMeta returns a small piece of worker metadata, or "" if unset.
+func (s *Postgres) GetMeta(metaKey string) (string, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM kv_meta WHERE key = $1`, metaKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetMeta persists a small piece of worker metadata.
+func (s *Postgres) SetMeta(metaKey string, value string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO kv_meta (key, value)
+		VALUES ($1, $2)
+		ON CONFLICT (key)
+		DO UPDATE SET value = $2
+	`, metaKey, value)
+	return err
+}
+
+// escapeLikePattern escapes LIKE metacharacters (and the escape character
+// itself) in prefix so it matches only literal characters, the same
+// semantics as bolt.Scan/memory.Scan's HasPrefix.
+func escapeLikePattern(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(prefix)
+}
+
+// Scan returns up to limit alive keys with the given prefix, starting at
+// startKey (inclusive), ordered lexicographically by key.
+func (s *Postgres) Scan(prefix string, startKey string, limit int) ([]store.KV, error) {
+	rows, err := s.db.Query(`
+		SELECT key, value, expires_at
+		FROM kv
+		WHERE key >= $1 AND key LIKE $2 || '%' ESCAPE '\'
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY key
+		LIMIT $3
+	`, startKey, escapeLikePattern(prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []store.KV
+	for rows.Next() {
+		var kv store.KV
+		if err := rows.Scan(&kv.Key, &kv.Value, &kv.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, kv)
+	}
+	return out, rows.Err()
+}
+
+// Watch streams the value of key every time it changes. Postgres has no
+// lightweight per-key change feed wired up here, so this polls Get.
+func (s *Postgres) Watch(ctx context.Context, key string) (<-chan []byte, error) {
+	return store.PollWatch(ctx, 500*time.Millisecond, func() ([]byte, error) {
+		value, _, err := s.Get(key)
+		return value, err
+	}), nil
+}
+
+// IncrBy atomically adds delta to the integer stored at key and returns the
+// new value, treating a missing/tombstoned/expired key as 0. The whole
+// read-modify-write happens in one statement so concurrent IncrBy calls
+// never race.
+func (s *Postgres) IncrBy(key string, delta int64) (int64, *time.Time, error) {
+	var raw []byte
+	var expiresAt sql.NullTime
+	err := s.db.QueryRow(`
+		INSERT INTO kv (key, value, expires_at)
+		VALUES ($1, convert_to($2::text, 'UTF8'), NULL)
+		ON CONFLICT (key) DO UPDATE
+		SET value = convert_to((convert_from(kv.value, 'UTF8')::bigint + $2)::text, 'UTF8')
+		WHERE kv.expires_at IS NULL OR kv.expires_at > NOW()
+		RETURNING value, expires_at
+	`, key, delta).Scan(&raw, &expiresAt)
+
+	if err == sql.ErrNoRows {
+		// The existing row is tombstoned or expired, so the upsert above
+		// left it untouched (ON CONFLICT ... WHERE was false). Treat it as
+		// absent: reinitialize it to delta and clear its TTL.
+		err = s.db.QueryRow(`
+			UPDATE kv
+			SET value = convert_to($2::text, 'UTF8'), expires_at = NULL
+			WHERE key = $1
+			RETURNING value, expires_at
+		`, key, delta).Scan(&raw, &expiresAt)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	newValue, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var newExpiresAt *time.Time
+	if expiresAt.Valid {
+		t := expiresAt.Time
+		newExpiresAt = &t
+	}
+
+	return newValue, newExpiresAt, nil
+}
+
+// Policies returns every registered retention policy.
+func (s *Postgres) Policies() ([]retention.Policy, error) {
+	rows, err := s.db.Query(`SELECT data FROM kv_retention_policy ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []retention.Policy
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var p retention.Policy
+		if err := p.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// SavePolicy creates or replaces the policy with the given name. Policies
+// are stored gob-encoded (see retention.Policy.MarshalBinary) rather than
+// as separate columns, so the row is a single opaque blob that's easy to
+// replicate or back up alongside the rest of the store.
+func (s *Postgres) SavePolicy(p retention.Policy) error {
+	data, err := p.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO kv_retention_policy (name, data)
+		VALUES ($1, $2)
+		ON CONFLICT (name)
+		DO UPDATE SET data = $2
+	`, p.Name, data)
+	return err
+}
+
+// GetDB returns the underlying database connection
+// Useful for running custom queries or migrations
+func (s *Postgres) GetDB() *sql.DB {
+	return s.db
+}
@@ -1,7 +1,8 @@
 package store
 
 import (
-	"database/sql"
+	"bytes"
+	"context"
 	"time"
 )
 
@@ -9,138 +10,128 @@ import (
 // Any key with expires_at = TombstoneTime is considered deleted
 var TombstoneTime = time.Unix(0, 0).UTC()
 
-// Store handles all PostgreSQL operations for the KV store
-type Store struct {
-	db *sql.DB
+// KV is a single key/value row as returned by Scan, independent of backend.
+type KV struct {
+	Key       string
+	Value     []byte
+	ExpiresAt *time.Time
 }
 
-// New creates a new Store instance
-func New(db *sql.DB) *Store {
-	return &Store{db: db}
+// Store is the storage backend seam used by engine.Engine and expiry.Worker.
+// Every implementation (postgres, bolt, memory, ...) must honor the same
+// tombstone/TTL semantics: a key is alive when its expiry is nil or in the
+// future, and dead (soft-deleted or expired) otherwise.
+type Store interface {
+	// Get retrieves a key.
+	// Returns (value, expiresAt, nil) if found.
+	// Returns (nil, nil, nil) if not found, tombstoned, or expired.
+	Get(key string) ([]byte, *time.Time, error)
+
+	// Set stores a key-value pair without TTL.
+	Set(key string, value []byte) error
+
+	// SetWithTTL stores a key-value pair with a TTL in seconds and returns
+	// the computed expiry time.
+	SetWithTTL(key string, value []byte, ttlSeconds int64) (time.Time, error)
+
+	// SoftDelete marks a key as deleted by setting its expiry to TombstoneTime.
+	// This is a cheap operation - no tree rebalancing.
+	SoftDelete(key string) error
+
+	// HardDelete physically removes a key. Use sparingly.
+	HardDelete(key string) error
+
+	// HardDeleteBatch removes up to limit expired/tombstoned keys whose key
+	// is greater than cursor, walking keys in order, and returns how many
+	// were deleted along with the last key deleted (lastKey is "" if
+	// nothing was deleted). This is the only place where physical deletes
+	// should happen in bulk, and the cursor makes repeated calls resumable:
+	// pass the previous lastKey back in to continue the sweep.
+	HardDeleteBatch(cursor string, limit int) (deleted int64, lastKey string, err error)
+
+	// CountKeysWithTTL returns how many keys currently carry a TTL
+	// (including tombstones). Used by the expiry worker to pace its sweep.
+	CountKeysWithTTL() (int64, error)
+
+	// GetMeta returns a small piece of worker metadata, or "" if unset.
+	GetMeta(metaKey string) (string, error)
+
+	// SetMeta persists a small piece of worker metadata.
+	SetMeta(metaKey string, value string) error
+
+	// Scan returns up to limit alive keys with the given prefix, starting
+	// at startKey (inclusive), ordered lexicographically by key.
+	Scan(prefix string, startKey string, limit int) ([]KV, error)
+
+	// Watch streams the value of key every time it changes. The returned
+	// channel is closed once ctx is done.
+	Watch(ctx context.Context, key string) (<-chan []byte, error)
 }
 
-// Get retrieves a key from the database
-// Only returns keys that are alive (not tombstoned, not expired)
-// Returns (value, expiresAt, nil) if found
-// Returns (nil, nil, nil) if not found or expired/deleted
-func (s *Store) Get(key string) ([]byte, *time.Time, error) {
-	var value []byte
-	var expiresAt *time.Time
-
-	// Only select keys that are alive:
-	// - expires_at IS NULL (no TTL, not deleted)
-	// - expires_at > NOW() (TTL in future)
-	err := s.db.QueryRow(`
-		SELECT value, expires_at 
-		FROM kv 
-		WHERE key = $1 
-		  AND (expires_at IS NULL OR expires_at > NOW())
-	`, key).Scan(&value, &expiresAt)
-
-	if err == sql.ErrNoRows {
-		return nil, nil, nil
-	}
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return value, expiresAt, nil
+// TxStore is the subset of Store available inside a transaction started by
+// Transactional.WithTx. It deliberately excludes bulk/maintenance
+// operations (HardDeleteBatch, Scan, Watch) that don't make sense
+// mid-transaction.
+type TxStore interface {
+	Get(key string) ([]byte, *time.Time, error)
+	Set(key string, value []byte) error
+	SetWithTTL(key string, value []byte, ttlSeconds int64) (time.Time, error)
+	SoftDelete(key string) error
 }
 
-// Set stores a key-value pair without TTL
-func (s *Store) Set(key string, value []byte) error {
-	_, err := s.db.Exec(`
-		INSERT INTO kv (key, value, expires_at) 
-		VALUES ($1, $2, NULL)
-		ON CONFLICT (key) 
-		DO UPDATE SET value = $2, expires_at = NULL
-	`, key, value)
-	return err
+// Transactional is implemented by backends that can run a sequence of
+// writes atomically. fn's writes are committed if it returns nil, and
+// rolled back otherwise.
+type Transactional interface {
+	WithTx(fn func(tx TxStore) error) error
 }
 
-// SetWithTTL stores a key-value pair with a TTL
-func (s *Store) SetWithTTL(key string, value []byte, ttlSeconds int64) (time.Time, error) {
-	expiresAt := time.Now().Add(time.Duration(ttlSeconds) * time.Second)
-
-	_, err := s.db.Exec(`
-		INSERT INTO kv (key, value, expires_at) 
-		VALUES ($1, $2, $3)
-		ON CONFLICT (key) 
-		DO UPDATE SET value = $2, expires_at = $3
-	`, key, value, expiresAt)
-
-	return expiresAt, err
-}
-
-// SoftDelete marks a key as deleted by setting expires_at to TombstoneTime
-// This is a cheap UPDATE operation - no B+tree rebalancing
-func (s *Store) SoftDelete(key string) error {
-	_, err := s.db.Exec(`
-		UPDATE kv 
-		SET expires_at = $1 
-		WHERE key = $2
-	`, TombstoneTime, key)
-	return err
-}
-
-// HardDelete physically removes a key from the database
-// Use sparingly - causes B+tree rebalancing
-func (s *Store) HardDelete(key string) error {
-	_, err := s.db.Exec(`DELETE FROM kv WHERE key = $1`, key)
-	return err
+// Counter is implemented by backends that can perform an atomic
+// read-modify-write increment, which Get/Set alone cannot express safely.
+// A dead (missing, tombstoned, or expired) key is treated as 0 and its TTL
+// is cleared. IncrBy returns the resulting expiry (nil if the key carries
+// no TTL) so callers can keep a cache entry in sync with the stored row.
+type Counter interface {
+	IncrBy(key string, delta int64) (int64, *time.Time, error)
 }
 
-// HardDeleteBatch removes multiple expired/tombstoned keys in a single operation
-// This is the only place where physical deletes should happen
-// Returns the number of rows deleted
-func (s *Store) HardDeleteBatch(limit int) (int64, error) {
-	result, err := s.db.Exec(`
-		DELETE FROM kv 
-		WHERE key IN (
-			SELECT key FROM kv 
-			WHERE expires_at IS NOT NULL 
-			  AND expires_at <= NOW() 
-			LIMIT $1
-		)
-	`, limit)
-	if err != nil {
-		return 0, err
-	}
-	return result.RowsAffected()
-}
-
-// SampleExpiredKeys returns a random sample of keys with TTL
-// Used for Redis-style probabilistic expiration
-func (s *Store) SampleExpiredKeys(sampleSize int) (total int, expired int, err error) {
-	rows, err := s.db.Query(`
-		SELECT expires_at 
-		FROM kv 
-		WHERE expires_at IS NOT NULL 
-		ORDER BY random() 
-		LIMIT $1
-	`, sampleSize)
-	if err != nil {
-		return 0, 0, err
-	}
-	defer rows.Close()
-
-	now := time.Now()
-	for rows.Next() {
-		var expiresAt time.Time
-		if err := rows.Scan(&expiresAt); err != nil {
-			continue
-		}
-		total++
-		if now.After(expiresAt) {
-			expired++
+// PollWatch implements Watch by polling get at the given interval and
+// emitting a value on the returned channel whenever it changes. It is
+// shared by backends (bolt, memory, postgres) that have no native change
+// feed wired up.
+func PollWatch(ctx context.Context, interval time.Duration, get func() ([]byte, error)) <-chan []byte {
+	ch := make(chan []byte)
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last []byte
+		var haveLast bool
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				value, err := get()
+				if err != nil {
+					continue
+				}
+				if haveLast && bytes.Equal(last, value) {
+					continue
+				}
+				last, haveLast = value, true
+				select {
+				case ch <- value:
+				case <-ctx.Done():
+					return
+				}
+			}
 		}
-	}
-
-	return total, expired, rows.Err()
-}
+	}()
 
-// GetDB returns the underlying database connection
-// Useful for running custom queries or migrations
-func (s *Store) GetDB() *sql.DB {
-	return s.db
+	return ch
 }